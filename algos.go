@@ -0,0 +1,104 @@
+// Algorithm dispatch for call-graph construction.
+//
+// go-callvis supports the same set of call-graph construction algorithms as
+// golang.org/x/tools/cmd/callgraph: static, cha, rta and pta. static/cha/rta
+// are cheap, sound-ish approximations that work on libraries (no main
+// package required); pta runs the full pointer analysis and additionally
+// resolves dynamic (interface/closure) call sites, but requires at least one
+// main package and is considerably more expensive.
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/cha"
+	"golang.org/x/tools/go/callgraph/rta"
+	"golang.org/x/tools/go/callgraph/static"
+	"golang.org/x/tools/go/pointer"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+const (
+	AlgoStatic = "static"
+	AlgoCha    = "cha"
+	AlgoRta    = "rta"
+	AlgoPta    = "pta"
+)
+
+// callgraphFor builds a *callgraph.Graph for pkgs/mains using the named
+// algorithm. ptrResult is non-nil only when algo is AlgoPta, and is kept
+// around so callers can resolve indirect call sites when rendering.
+func callgraphFor(algo string, prog *ssa.Program, pkgs, mains []*ssa.Package) (*callgraph.Graph, *pointer.Result, error) {
+	switch algo {
+	case AlgoStatic:
+		return static.CallGraph(prog), nil, nil
+
+	case AlgoCha:
+		return cha.CallGraph(prog), nil, nil
+
+	case AlgoRta:
+		roots := rtaRoots(pkgs, mains)
+		if len(roots) == 0 {
+			return nil, nil, fmt.Errorf("rta: no root functions found")
+		}
+		return rta.Analyze(roots, true).CallGraph, nil, nil
+
+	case AlgoPta:
+		if len(mains) == 0 {
+			return nil, nil, fmt.Errorf("pta: no main packages (try -algo=static, cha or rta)")
+		}
+		ptrcfg := &pointer.Config{
+			Mains:          mains,
+			BuildCallGraph: true,
+		}
+		// Query the receiver of every interface method call so dotGraph can
+		// label dynamic edges with the receiver's concrete types.
+		for fn := range ssautil.AllFunctions(prog) {
+			for _, b := range fn.Blocks {
+				for _, instr := range b.Instrs {
+					if site, ok := instr.(ssa.CallInstruction); ok && site.Common().IsInvoke() {
+						ptrcfg.AddQuery(site.Common().Value)
+					}
+				}
+			}
+		}
+		result, err := pointer.Analyze(ptrcfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return result.CallGraph, result, nil
+
+	default:
+		return nil, nil, fmt.Errorf("invalid -algo value %q (want static, cha, rta or pta)", algo)
+	}
+}
+
+// rtaRoots picks the set of root functions to drive RTA from: the main/init
+// functions of any main packages, plus every exported function of every
+// package when there are no mains (e.g. when analyzing a library).
+func rtaRoots(pkgs, mains []*ssa.Package) []*ssa.Function {
+	var roots []*ssa.Function
+
+	for _, main := range mains {
+		if fn := main.Func("main"); fn != nil {
+			roots = append(roots, fn)
+		}
+		if fn := main.Func("init"); fn != nil {
+			roots = append(roots, fn)
+		}
+	}
+
+	if len(roots) == 0 {
+		for _, pkg := range pkgs {
+			for _, member := range pkg.Members {
+				if fn, ok := member.(*ssa.Function); ok && fn.Object() != nil && fn.Object().Exported() {
+					roots = append(roots, fn)
+				}
+			}
+		}
+	}
+
+	return roots
+}
@@ -0,0 +1,201 @@
+// Graphviz DOT rendering, shared by the default CLI output, -http and
+// -format=dot/svg/png.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/pointer"
+	"golang.org/x/tools/go/ssa"
+)
+
+// Graphviz options, set from -minlen/-nodesep.
+var (
+	minlen  uint
+	nodesep float64
+)
+
+// dotNode is a single graph node pending render: a function, its label, and
+// the cluster (if any) it should be grouped into.
+type dotNode struct {
+	id, label, cluster, url string
+}
+
+// dotGraph renders cg as Graphviz DOT source. Edges and nodes are filtered
+// by focusPkg (when non-nil, only edges touching it are kept), limitPaths/
+// ignorePaths (package path prefixes) and nostd (drops std-library nodes).
+// ptrResult is only present under -algo=pta; when non-nil, it's used to
+// label each interface method call edge with the concrete receiver types
+// the pointer analysis resolved the call site to. Function-value call edges
+// are drawn dashed but get no label. groupBy["pkg"]/groupBy["type"] cluster nodes into Graphviz
+// subgraphs by package or by receiver type. When clickable is true, each
+// node gets a URL pointing back at "/" with focus set to that node's
+// package, for use from the -http UI.
+func dotGraph(pkg *types.Package, cg *callgraph.Graph, ptrResult *pointer.Result, focusPkg *packages.Package, limitPaths, ignorePaths []string, groupBy map[string]bool, nostd, clickable bool) ([]byte, error) {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "digraph %q {\n", pkg.Path())
+	fmt.Fprintf(&buf, "\trankdir=\"LR\";\n\tminlen=%d;\n\tnodesep=%v;\n", minlen, nodesep)
+
+	nodes := make(map[string]dotNode)
+	var edges []string
+
+	err := callgraph.GraphVisitEdges(cg, func(edge *callgraph.Edge) error {
+		caller, callee := edge.Caller.Func, edge.Callee.Func
+		if caller.Pkg == nil || callee.Pkg == nil {
+			return nil // synthetic wrappers etc.
+		}
+		if nostd && (isStdFunc(caller) || isStdFunc(callee)) {
+			return nil
+		}
+		if !matchesFilters(caller.Pkg.Pkg.Path(), limitPaths, ignorePaths) ||
+			!matchesFilters(callee.Pkg.Pkg.Path(), limitPaths, ignorePaths) {
+			return nil
+		}
+		if focusPkg != nil && caller.Pkg.Pkg.Path() != focusPkg.PkgPath && callee.Pkg.Pkg.Path() != focusPkg.PkgPath {
+			return nil
+		}
+
+		for _, fn := range []*ssa.Function{caller, callee} {
+			id := fn.String()
+			if _, ok := nodes[id]; !ok {
+				nodes[id] = describeNode(fn, groupBy, clickable)
+			}
+		}
+
+		attrs := `style="solid"`
+		if edge.Site != nil && edge.Site.Common().StaticCallee() == nil {
+			attrs = `style="dashed"` // dynamically dispatched
+			if ptrResult != nil {
+				if calleeTypes := dynamicCalleeTypes(ptrResult, edge); len(calleeTypes) > 0 {
+					label := strings.Join(calleeTypes, "\\n")
+					attrs += fmt.Sprintf(`, label=%q, tooltip="resolved via pointer analysis"`, label)
+				}
+			}
+		}
+		edges = append(edges, fmt.Sprintf("\t%q -> %q [%s];\n", caller.String(), callee.String(), attrs))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	writeClusteredNodes(&buf, nodes)
+	sort.Strings(edges)
+	for _, e := range edges {
+		buf.WriteString(e)
+	}
+
+	fmt.Fprintln(&buf, "}")
+	return buf.Bytes(), nil
+}
+
+// describeNode computes fn's label and, when groupBy asks for it, the
+// cluster it belongs to: its receiver type for groupBy["type"], or its
+// package for groupBy["pkg"] (type takes precedence for methods, since it's
+// the more specific grouping).
+func describeNode(fn *ssa.Function, groupBy map[string]bool, clickable bool) dotNode {
+	label := fn.Name()
+
+	var recvType string
+	if recv := fn.Signature.Recv(); recv != nil {
+		recvType = types.TypeString(recv.Type(), nil)
+		label = fmt.Sprintf("(%s).%s", recvType, label)
+	}
+
+	var cluster string
+	switch {
+	case groupBy["type"] && recvType != "":
+		cluster = recvType
+	case groupBy["pkg"]:
+		cluster = fn.Pkg.Pkg.Path()
+	}
+
+	n := dotNode{id: fn.String(), label: label, cluster: cluster}
+	if clickable {
+		n.url = "/?focus=" + fn.Pkg.Pkg.Path()
+	}
+	return n
+}
+
+// writeClusteredNodes emits each node, grouping nodes that share a non-empty
+// cluster into their own "subgraph cluster_N { ... }" block. Iteration order
+// is sorted so repeated renders of the same graph produce identical DOT.
+func writeClusteredNodes(buf *bytes.Buffer, nodes map[string]dotNode) {
+	byCluster := make(map[string][]dotNode)
+	for _, n := range nodes {
+		byCluster[n.cluster] = append(byCluster[n.cluster], n)
+	}
+
+	var clusters []string
+	for c := range byCluster {
+		clusters = append(clusters, c)
+	}
+	sort.Strings(clusters)
+
+	clusterIdx := 0
+	for _, cluster := range clusters {
+		ns := byCluster[cluster]
+		sort.Slice(ns, func(i, j int) bool { return ns[i].id < ns[j].id })
+
+		indent := "\t"
+		if cluster != "" {
+			fmt.Fprintf(buf, "\tsubgraph cluster_%d {\n\t\tlabel=%q;\n", clusterIdx, cluster)
+			clusterIdx++
+			indent = "\t\t"
+		}
+		for _, n := range ns {
+			writeNode(buf, indent, n)
+		}
+		if cluster != "" {
+			fmt.Fprintln(buf, "\t}")
+		}
+	}
+}
+
+func writeNode(buf *bytes.Buffer, indent string, n dotNode) {
+	if n.url != "" {
+		fmt.Fprintf(buf, "%s%q [label=%q, URL=%q, target=\"_top\"];\n", indent, n.id, n.label, n.url)
+	} else {
+		fmt.Fprintf(buf, "%s%q [label=%q];\n", indent, n.id, n.label)
+	}
+}
+
+// dynamicCalleeTypes returns the concrete types the pointer analysis found
+// flowing into the receiver of edge's interface method call, sorted for
+// deterministic output. It returns nil if the call site's value isn't one
+// of ptrResult's queries; callgraphFor queries only interface method call
+// receivers, so function-value calls always yield nil.
+func dynamicCalleeTypes(ptrResult *pointer.Result, edge *callgraph.Edge) []string {
+	call := edge.Site.Common()
+
+	ptr, ok := ptrResult.Queries[call.Value]
+	if !ok {
+		ptr, ok = ptrResult.IndirectQueries[call.Value]
+		if !ok {
+			return nil
+		}
+	}
+
+	var names []string
+	ptr.PointsTo().DynamicTypes().Iterate(func(t types.Type, _ interface{}) {
+		names = append(names, t.String())
+	})
+	sort.Strings(names)
+	return names
+}
+
+func isStdFunc(fn *ssa.Function) bool {
+	return fn.Pkg != nil && isStdPath(fn.Pkg.Pkg.Path())
+}
+
+func isStdPath(path string) bool {
+	first := strings.SplitN(path, "/", 2)[0]
+	return path != "" && !strings.Contains(first, ".")
+}
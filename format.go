@@ -0,0 +1,150 @@
+// Output formats for the default (non -http) code path, selected with
+// -format.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/types"
+	"io"
+	"os/exec"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/pointer"
+	"golang.org/x/tools/go/ssa"
+)
+
+const (
+	FormatDot  = "dot"
+	FormatSvg  = "svg"
+	FormatPng  = "png"
+	FormatJson = "json"
+)
+
+// writeOutput renders cg, after focus/limit/ignore/group/nostd filtering, to
+// w in the requested format.
+func writeOutput(w io.Writer, format string, pkg *types.Package, cg *callgraph.Graph, ptrResult *pointer.Result, focusPkg *packages.Package, limitPaths, ignorePaths []string, groupBy map[string]bool, nostd bool) error {
+	switch format {
+	case FormatDot, FormatSvg, FormatPng:
+		dot, err := dotGraph(pkg, cg, ptrResult, focusPkg, limitPaths, ignorePaths, groupBy, nostd, false)
+		if err != nil {
+			return err
+		}
+		if format == FormatDot {
+			_, err := w.Write(dot)
+			return err
+		}
+		return renderGraphviz(w, dot, format)
+
+	case FormatJson:
+		return writeJSON(w, cg, focusPkg, limitPaths, ignorePaths, nostd)
+
+	default:
+		return fmt.Errorf("invalid -format value %q (want dot, svg, png or json)", format)
+	}
+}
+
+// renderGraphviz pipes dot source through the "dot" command, requesting the
+// given output type ("svg" or "png"), and streams the result to w.
+func renderGraphviz(w io.Writer, dot []byte, format string) error {
+	cmd := exec.Command("dot", "-T"+format)
+	cmd.Stdin = bytes.NewReader(dot)
+	cmd.Stdout = w
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("dot: %v: %s", err, stderr.String())
+	}
+	return nil
+}
+
+type jsonNode struct {
+	ID       int    `json:"id"`
+	Pkg      string `json:"pkg"`
+	Func     string `json:"func"`
+	Recv     string `json:"recv,omitempty"`
+	Exported bool   `json:"exported"`
+}
+
+type jsonEdge struct {
+	Caller  int    `json:"caller"`
+	Callee  int    `json:"callee"`
+	Site    string `json:"site,omitempty"`
+	Dynamic bool   `json:"dynamic"`
+}
+
+type jsonCallgraph struct {
+	Nodes []jsonNode `json:"nodes"`
+	Edges []jsonEdge `json:"edges"`
+}
+
+// writeJSON encodes cg, after focus/limit/ignore/nostd filtering, as a
+// {nodes, edges} object (see jsonCallgraph), so that other tools can
+// consume a go-callvis run without depending on Graphviz.
+func writeJSON(w io.Writer, cg *callgraph.Graph, focusPkg *packages.Package, limitPaths, ignorePaths []string, nostd bool) error {
+	ids := make(map[string]int)
+	var g jsonCallgraph
+
+	id := func(fn *ssa.Function) int {
+		key := fn.String()
+		if id, ok := ids[key]; ok {
+			return id
+		}
+		id := len(g.Nodes)
+		ids[key] = id
+
+		var recv string
+		if r := fn.Signature.Recv(); r != nil {
+			recv = types.TypeString(r.Type(), nil)
+		}
+		g.Nodes = append(g.Nodes, jsonNode{
+			ID:       id,
+			Pkg:      fn.Pkg.Pkg.Path(),
+			Func:     fn.Name(),
+			Recv:     recv,
+			Exported: fn.Object() != nil && fn.Object().Exported(),
+		})
+		return id
+	}
+
+	err := callgraph.GraphVisitEdges(cg, func(edge *callgraph.Edge) error {
+		caller, callee := edge.Caller.Func, edge.Callee.Func
+		if caller.Pkg == nil || callee.Pkg == nil {
+			return nil
+		}
+		if nostd && (isStdFunc(caller) || isStdFunc(callee)) {
+			return nil
+		}
+		if !matchesFilters(caller.Pkg.Pkg.Path(), limitPaths, ignorePaths) ||
+			!matchesFilters(callee.Pkg.Pkg.Path(), limitPaths, ignorePaths) {
+			return nil
+		}
+		if focusPkg != nil && caller.Pkg.Pkg.Path() != focusPkg.PkgPath && callee.Pkg.Pkg.Path() != focusPkg.PkgPath {
+			return nil
+		}
+
+		var site string
+		var dynamic bool
+		if edge.Site != nil {
+			site = caller.Prog.Fset.Position(edge.Site.Pos()).String()
+			dynamic = edge.Site.Common().StaticCallee() == nil
+		}
+
+		g.Edges = append(g.Edges, jsonEdge{
+			Caller:  id(caller),
+			Callee:  id(callee),
+			Site:    site,
+			Dynamic: dynamic,
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(g)
+}
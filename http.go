@@ -0,0 +1,189 @@
+// Interactive web UI, enabled with -http.
+package main
+
+import (
+	"go/types"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/pointer"
+	"golang.org/x/tools/go/ssa"
+)
+
+// server holds the SSA program built once at startup, plus a cache of call
+// graphs already computed per algorithm - so switching -algo in the web UI
+// only redoes the analysis the first time it's requested, while focus/group/
+// limit/ignore/nostd changes always just re-render the cached graph.
+type server struct {
+	prog        *ssa.Program
+	pkgs, mains []*ssa.Package
+	pkg         *types.Package
+	initial     []*packages.Package
+
+	mu    sync.Mutex
+	cache map[string]cachedGraph
+}
+
+type cachedGraph struct {
+	cg  *callgraph.Graph
+	ptr *pointer.Result
+}
+
+func (s *server) graphFor(algo string) (*callgraph.Graph, *pointer.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.cache[algo]; ok {
+		return c.cg, c.ptr, nil
+	}
+	cg, ptr, err := callgraphFor(algo, s.prog, s.pkgs, s.mains)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.cache[algo] = cachedGraph{cg, ptr}
+	return cg, ptr, nil
+}
+
+// serve builds a *server around the already-analyzed program and blocks
+// serving the interactive UI at addr.
+func serve(addr string, prog *ssa.Program, pkgs, mains []*ssa.Package, pkg *types.Package, initial []*packages.Package, algo string, focusPkg *packages.Package, groupBy map[string]bool, limitPaths, ignorePaths []string, nostd bool) error {
+	s := &server{
+		prog: prog, pkgs: pkgs, mains: mains, pkg: pkg, initial: initial,
+		cache: make(map[string]cachedGraph),
+	}
+
+	// Warm the cache with the algorithm requested on the command line so the
+	// first page load doesn't pay for analysis on top of rendering.
+	if _, _, err := s.graphFor(algo); err != nil {
+		return err
+	}
+
+	http.HandleFunc("/", s.handleIndex(algo, focusPkg, groupBy, limitPaths, ignorePaths, nostd))
+	http.HandleFunc("/svg", s.handleSVG())
+
+	log.Printf("go-callvis: serving at http://%s", addr)
+	return http.ListenAndServe(addr, nil)
+}
+
+func (s *server) handleIndex(defaultAlgo string, defaultFocus *packages.Package, defaultGroup map[string]bool, defaultLimit, defaultIgnore []string, defaultNostd bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		form := formValues{
+			Algo:   queryOr(q, "algo", defaultAlgo),
+			Focus:  queryOr(q, "focus", focusPkgPath(defaultFocus)),
+			Group:  queryOr(q, "group", joinGroup(defaultGroup)),
+			Limit:  queryOr(q, "limit", joinComma(defaultLimit)),
+			Ignore: queryOr(q, "ignore", joinComma(defaultIgnore)),
+			Nostd:  queryBoolOr(q, "nostd", defaultNostd),
+		}
+
+		if err := indexTmpl.Execute(w, form); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
+func (s *server) handleSVG() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		algo := queryOr(q, "algo", AlgoPta)
+		cg, ptrResult, err := s.graphFor(algo)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var focusPkg *packages.Package
+		if focus := q.Get("focus"); focus != "" {
+			focusPkg, err = findFocusPkg(s.initial, focus)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		groupBy, err := parseGroupBy(q.Get("group"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		dot, err := dotGraph(s.pkg, cg, ptrResult, focusPkg, splitList(q.Get("limit")), splitList(q.Get("ignore")), groupBy, queryBoolOr(q, "nostd", false), true)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "image/svg+xml")
+		if err := renderGraphviz(w, dot, FormatSvg); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
+type formValues struct {
+	Algo, Focus, Group, Limit, Ignore string
+	Nostd                             bool
+}
+
+var indexTmpl = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head><title>go-callvis</title></head>
+<body>
+	<form method="GET" action="/">
+		<label>algo <select name="algo">
+			<option value="static" {{if eq .Algo "static"}}selected{{end}}>static</option>
+			<option value="cha" {{if eq .Algo "cha"}}selected{{end}}>cha</option>
+			<option value="rta" {{if eq .Algo "rta"}}selected{{end}}>rta</option>
+			<option value="pta" {{if eq .Algo "pta"}}selected{{end}}>pta</option>
+		</select></label>
+		<label>focus <input type="text" name="focus" value="{{.Focus}}"></label>
+		<label>group <input type="text" name="group" value="{{.Group}}"></label>
+		<label>limit <input type="text" name="limit" value="{{.Limit}}"></label>
+		<label>ignore <input type="text" name="ignore" value="{{.Ignore}}"></label>
+		<label><input type="checkbox" name="nostd" value="1" {{if .Nostd}}checked{{end}}> nostd</label>
+		<button type="submit">refresh</button>
+	</form>
+	<img src="/svg?algo={{.Algo}}&focus={{.Focus}}&group={{.Group}}&limit={{.Limit}}&ignore={{.Ignore}}&nostd={{if .Nostd}}1{{end}}">
+</body>
+</html>
+`))
+
+func focusPkgPath(p *packages.Package) string {
+	if p == nil {
+		return ""
+	}
+	return p.PkgPath
+}
+
+func joinGroup(groupBy map[string]bool) string {
+	var vals []string
+	for _, k := range []string{"pkg", "type"} {
+		if groupBy[k] {
+			vals = append(vals, k)
+		}
+	}
+	return joinComma(vals)
+}
+
+func queryOr(q url.Values, key, def string) string {
+	if v := q.Get(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func queryBoolOr(q url.Values, key string, def bool) bool {
+	vs, ok := q[key]
+	if !ok || len(vs) == 0 {
+		return def
+	}
+	return vs[0] == "1" || vs[0] == "true"
+}
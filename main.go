@@ -1,17 +1,16 @@
 // go-callvis: a tool to help visualize the call graph of a Go program.
-//
 package main
 
 import (
 	"flag"
 	"fmt"
-	"go/build"
+	"go/types"
 	"log"
+	"os"
 	"strings"
 	"time"
 
-	"golang.org/x/tools/go/loader"
-	"golang.org/x/tools/go/pointer"
+	"golang.org/x/tools/go/packages"
 	"golang.org/x/tools/go/ssa"
 	"golang.org/x/tools/go/ssa/ssautil"
 )
@@ -19,6 +18,9 @@ import (
 var Version = "0.0.0-src"
 
 var (
+	algoFlag      = flag.String("algo", AlgoPta, "Call graph construction algorithm (static, cha, rta, pta).")
+	httpFlag      = flag.String("http", "", "Serve an interactive web UI at the given address, e.g. -http=:7878.")
+	formatFlag    = flag.String("format", FormatSvg, "Output format: dot, svg, png or json.")
 	focusFlag     = flag.String("focus", "main", "Focus package with name or import path.")
 	limitFlag     = flag.String("limit", "", "Limit package paths to prefix. (separate multiple by comma)")
 	groupFlag     = flag.String("group", "", "Grouping functions by [pkg, type] (separate multiple by comma).")
@@ -41,123 +43,60 @@ func main() {
 		fmt.Println("go-callvis", Version)
 		return
 	} else {
-		var (
-			ctxt        *build.Context = &build.Default
-			groupBy     map[string]bool
-			limitPaths  []string
-			ignorePaths []string
-
-			value        string
-		)
-
 		if *debugFlag {
 			log.SetFlags(log.Lmicroseconds)
 		}
 
-		if "" != *groupFlag {
-			groupBy = make(map[string]bool)
-
-			for _, value = range strings.Split(*groupFlag, ",") {
-				if value = strings.TrimSpace(value); value == "" {
-					continue
-				} else if value != "pkg" && value != "type" {
-					log.Fatalln("go-callvis: invalid group option")
-				} else {
-					groupBy[value] = true
-				}
-			}
-		}
-
-		if "" != *limitFlag {
-			limitPaths = make([]string, 0)
-
-			for _, value = range strings.Split(*limitFlag, ",") {
-				if value = strings.TrimSpace(value); value != "" {
-					limitPaths = append(limitPaths, value)
-				}
-			}
-		}
-
-		if "" != *ignoreFlag {
-			ignorePaths = make([]string, 0)
-
-			for _, value = range strings.Split(*ignoreFlag, ",") {
-				if value = strings.TrimSpace(value); value != "" {
-					ignorePaths = append(ignorePaths, value)
-				}
-			}
+		groupBy, err := parseGroupBy(*groupFlag)
+		if err != nil {
+			log.Fatalln("go-callvis:", err.Error())
 		}
+		limitPaths := splitList(*limitFlag)
+		ignorePaths := splitList(*ignoreFlag)
 
-		// Build tags.
+		var buildFlags []string
 		if "" != *buildTagsFlag {
-			ctxt.BuildTags = make([]string, 0)
-
-			for _, value = range strings.Split(*buildTagsFlag, ",") {
-				if value = strings.TrimSpace(value); value != "" {
-					ctxt.BuildTags = append(ctxt.BuildTags, value)
-				}
-			}
+			buildFlags = []string{"-tags=" + *buildTagsFlag}
 		}
 
-		if err := run(ctxt, *focusFlag, groupBy, limitPaths, ignorePaths, *nostdFlag, *testFlag, flag.Args()); err != nil {
+		if err := run(*algoFlag, *httpFlag, *formatFlag, *focusFlag, groupBy, limitPaths, ignorePaths, buildFlags, *nostdFlag, *testFlag, flag.Args()); err != nil {
 			log.Fatalln("go-callvis:", err.Error())
 		}
 	}
 }
 
-func run(ctxt *build.Context, focus string, groupBy map[string]bool, limitPaths, ignorePaths []string, nostd, tests bool, args []string) error {
+func run(algo, httpAddr, format, focus string, groupBy map[string]bool, limitPaths, ignorePaths []string, buildFlags []string, nostd, tests bool, args []string) error {
 	if len(args) == 0 {
 		return fmt.Errorf("missing arguments")
 	}
 
 	t0 := time.Now()
-	conf := loader.Config{Build: ctxt}
-	_, err := conf.FromArgs(args, tests)
+	expanded, err := expandPatterns(args, buildFlags, limitPaths, ignorePaths)
 	if err != nil {
 		return err
 	}
-	load, err := conf.Load()
+	logf("expanded %d pattern(s) to %d package(s)", len(args), len(expanded))
+
+	cfg := &packages.Config{
+		Mode:       packages.LoadAllSyntax,
+		Tests:      tests,
+		BuildFlags: buildFlags,
+	}
+	initial, err := packages.Load(cfg, expanded...)
 	if err != nil {
 		return err
 	}
+	if packages.PrintErrors(initial) > 0 {
+		return fmt.Errorf("packages contain errors")
+	}
 	logf("loading took: %v", time.Since(t0))
-	logf("%d imported (%d created)", len(load.Imported), len(load.Created))
+	logf("%d packages loaded", len(initial))
 
 	t0 = time.Now()
-	prog := ssautil.CreateProgram(load, 0)
+	prog, pkgs := ssautil.AllPackages(initial, 0)
 	prog.Build()
-	pkgs := prog.AllPackages()
 	logf("building took: %v", time.Since(t0))
 
-	var focusPkg *build.Package
-	if focus != "" {
-		focusPkg, err = conf.Build.Import(focus, "", 0)
-		if err != nil {
-			if strings.Contains(focus, "/") {
-				return err
-			}
-			// try to find package by name
-			var foundPaths []string
-			for _, p := range pkgs {
-				if p.Pkg.Name() == focus {
-					foundPaths = append(foundPaths, p.Pkg.Path())
-				}
-			}
-			if len(foundPaths) == 0 {
-				return err
-			} else if len(foundPaths) > 1 {
-				for _, p := range foundPaths {
-					log.Fatalf(" - %s\n", p)
-				}
-				return fmt.Errorf("found %d packages with name %q, use import path not name", len(foundPaths), focus)
-			}
-			if focusPkg, err = conf.Build.Import(foundPaths[0], "", 0); err != nil {
-				return err
-			}
-		}
-		logf("focusing: %v", focusPkg.ImportPath)
-	}
-
 	var mains []*ssa.Package
 	if tests {
 		for _, pkg := range pkgs {
@@ -170,27 +109,82 @@ func run(ctxt *build.Context, focus string, groupBy map[string]bool, limitPaths,
 		}
 	} else {
 		mains = append(mains, ssautil.MainPackages(pkgs)...)
-		if len(mains) == 0 {
+		if len(mains) == 0 && algo == AlgoPta {
 			return fmt.Errorf("no main packages")
 		}
 	}
 	logf("%d packages (%d main)", len(pkgs), len(mains))
 
-	t0 = time.Now()
-	ptrcfg := &pointer.Config{
-		Mains:          mains,
-		BuildCallGraph: true,
+	// "main" is the default focus, meant for the common main-package case;
+	// on a library with no main package it doesn't name anything, so fall
+	// back to no focus rather than erroring out on the default.
+	if focus == "main" && len(mains) == 0 {
+		focus = ""
+	}
+
+	var focusPkg *packages.Package
+	if focus != "" {
+		focusPkg, err = findFocusPkg(initial, focus)
+		if err != nil {
+			return err
+		}
+		logf("focusing: %v", focusPkg.PkgPath)
+	}
+
+	var pkg *types.Package
+	if len(mains) > 0 {
+		pkg = mains[0].Pkg
+	} else {
+		pkg = pkgs[0].Pkg
+	}
+
+	if httpAddr != "" {
+		return serve(httpAddr, prog, pkgs, mains, pkg, initial, algo, focusPkg, groupBy, limitPaths, ignorePaths, nostd)
 	}
-	result, err := pointer.Analyze(ptrcfg)
+
+	t0 = time.Now()
+	cg, ptrResult, err := callgraphFor(algo, prog, pkgs, mains)
 	if err != nil {
 		return err
 	}
 	logf("analysis took: %v", time.Since(t0))
 
-	return printOutput(mains[0].Pkg, result.CallGraph,
+	return writeOutput(os.Stdout, format, pkg, cg, ptrResult,
 		focusPkg, limitPaths, ignorePaths, groupBy, nostd)
 }
 
+// findFocusPkg resolves focus - an import path or a bare package name - to
+// one of the loaded packages.
+func findFocusPkg(initial []*packages.Package, focus string) (*packages.Package, error) {
+	for _, p := range initial {
+		if p.PkgPath == focus {
+			return p, nil
+		}
+	}
+	if strings.Contains(focus, "/") {
+		return nil, fmt.Errorf("no such package: %s", focus)
+	}
+
+	// try to find package by name
+	var found []*packages.Package
+	for _, p := range initial {
+		if p.Name == focus {
+			found = append(found, p)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("no such package: %s", focus)
+	case 1:
+		return found[0], nil
+	default:
+		for _, p := range found {
+			log.Printf(" - %s\n", p.PkgPath)
+		}
+		return nil, fmt.Errorf("found %d packages with name %q, use import path not name", len(found), focus)
+	}
+}
+
 func logf(f string, a ...interface{}) {
 	if *debugFlag {
 		log.Printf(f, a...)
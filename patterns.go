@@ -0,0 +1,73 @@
+// Expansion of go-tool-style import path patterns ("./...", "all", "std",
+// "github.com/x/y/...") into a concrete list of packages to load.
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// expandPatterns resolves patterns - which may use any of the wildcards the
+// go tool itself accepts ("./...", an import path ending in "/...", "all" or
+// "std") - into a de-duplicated list of concrete import paths. limitPaths
+// and ignorePaths are applied here, before the (expensive) full load, so
+// that packages excluded by a filter are never paid for.
+//
+// This expansion always runs with Tests:false: with Tests:true, packages.Load
+// also returns synthetic per-package entries (the "foo.test" test binary,
+// "foo [foo.test]" and "foo_test [foo.test]" variants) whose PkgPath isn't a
+// loadable pattern on its own. -tests is instead honored by the real load in
+// run, which loads these same import paths with Tests:true.
+func expandPatterns(patterns, buildFlags []string, limitPaths, ignorePaths []string) ([]string, error) {
+	cfg := &packages.Config{
+		Mode:       packages.LoadImports,
+		BuildFlags: buildFlags,
+	}
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	if packages.PrintErrors(pkgs) > 0 {
+		return nil, fmt.Errorf("packages contain errors")
+	}
+
+	seen := make(map[string]bool)
+	var paths []string
+	for _, p := range pkgs {
+		if seen[p.PkgPath] || !matchesFilters(p.PkgPath, limitPaths, ignorePaths) {
+			continue
+		}
+		seen[p.PkgPath] = true
+		paths = append(paths, p.PkgPath)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no packages matched %v", patterns)
+	}
+	return paths, nil
+}
+
+// matchesFilters reports whether path passes the -limit/-ignore prefix
+// filters: it must match one of limitPaths (if any are given), and none of
+// ignorePaths.
+func matchesFilters(path string, limitPaths, ignorePaths []string) bool {
+	if len(limitPaths) > 0 {
+		var ok bool
+		for _, l := range limitPaths {
+			if strings.HasPrefix(path, l) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	for _, ig := range ignorePaths {
+		if strings.HasPrefix(path, ig) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// splitList splits a comma-separated flag value into its trimmed,
+// non-empty parts. It returns nil for an empty string.
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// joinComma is the inverse of splitList.
+func joinComma(vals []string) string {
+	return strings.Join(vals, ",")
+}
+
+// parseGroupBy parses a -group flag value ("pkg", "type" or "pkg,type").
+func parseGroupBy(s string) (map[string]bool, error) {
+	vals := splitList(s)
+	if len(vals) == 0 {
+		return nil, nil
+	}
+	groupBy := make(map[string]bool)
+	for _, v := range vals {
+		if v != "pkg" && v != "type" {
+			return nil, fmt.Errorf("invalid group option %q", v)
+		}
+		groupBy[v] = true
+	}
+	return groupBy, nil
+}